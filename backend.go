@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	vaultapi "github.com/hashicorp/vault/api"
+	"google.golang.org/api/iterator"
+)
+
+// ErrIteratorDone is returned by VersionIterator.Next when there are no more
+// versions to yield.
+var ErrIteratorDone = errors.New("no more versions")
+
+// Version describes a single version of a vault known to a SecretBackend.
+// Name is the backend-specific identifier passed back into AccessVersion;
+// Number is the human-readable version string used in reports.
+type Version struct {
+	Name    string
+	Number  string
+	Enabled bool
+}
+
+// VersionIterator yields the versions of a vault one at a time.
+type VersionIterator interface {
+	// Next returns the next version, or ErrIteratorDone when exhausted.
+	Next() (Version, error)
+}
+
+// sliceVersionIterator adapts a pre-fetched []Version to VersionIterator.
+// All three backends list their versions eagerly, so they share this
+// instead of each hand-rolling a position-tracking iterator.
+type sliceVersionIterator struct {
+	versions []Version
+	pos      int
+}
+
+func (i *sliceVersionIterator) Next() (Version, error) {
+	if i.pos >= len(i.versions) {
+		return Version{}, ErrIteratorDone
+	}
+	v := i.versions[i.pos]
+	i.pos++
+	return v, nil
+}
+
+// sortVersionsDescending orders versions newest-first, matching GCP Secret
+// Manager's listing order. Version numbers are compared numerically when
+// both parse as integers (vault/file version numbers can reach double
+// digits, where a plain string compare would put "9" after "12"), falling
+// back to a string compare otherwise.
+func sortVersionsDescending(versions []Version) {
+	sort.Slice(versions, func(i, j int) bool {
+		ni, ierr := strconv.Atoi(versions[i].Number)
+		nj, jerr := strconv.Atoi(versions[j].Number)
+		if ierr == nil && jerr == nil {
+			return ni > nj
+		}
+		return versions[i].Number > versions[j].Number
+	})
+}
+
+// SecretBackend abstracts the secret store that findVaultVersion resolves
+// detector keys against. GCP Secret Manager, HashiCorp Vault KV v2, and a
+// local directory of JSON blobs all implement it so the rest of the tool
+// doesn't need to know which one it's talking to.
+type SecretBackend interface {
+	// ListVersions returns the versions of the named vault, most recent
+	// concerns (enabled/disabled, ordering) left to the backend.
+	ListVersions(ctx context.Context, vault string) (VersionIterator, error)
+	// AccessVersion returns the raw payload for a version returned by
+	// ListVersions.
+	AccessVersion(ctx context.Context, name string) ([]byte, error)
+}
+
+// ---- GCP Secret Manager backend (current behavior) ----
+
+// secretClient is the thin slice of the GCP Secret Manager client that
+// GCPBackend depends on. It exists so tests can swap in a fakeSecretClient
+// instead of hitting the network; realSecretClient is the only production
+// implementation.
+type secretClient interface {
+	AccessSecretVersion(ctx context.Context, name string) ([]byte, error)
+	ListSecretVersions(ctx context.Context, parent string) ([]Version, error)
+}
+
+// realSecretClient adapts *secretmanager.Client to secretClient, draining
+// the SDK's streaming iterator into a slice.
+type realSecretClient struct {
+	client *secretmanager.Client
+}
+
+func (c *realSecretClient) AccessSecretVersion(ctx context.Context, name string) ([]byte, error) {
+	result, err := c.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Payload.Data, nil
+}
+
+func (c *realSecretClient) ListSecretVersions(ctx context.Context, parent string) ([]Version, error) {
+	it := c.client.ListSecretVersions(ctx, &secretmanagerpb.ListSecretVersionsRequest{Parent: parent})
+
+	var versions []Version
+	for {
+		version, err := it.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				return versions, nil
+			}
+			return nil, err
+		}
+		versions = append(versions, Version{
+			Name:    version.Name,
+			Number:  getVersionNumber(version.Name),
+			Enabled: version.State == secretmanagerpb.SecretVersion_ENABLED,
+		})
+	}
+}
+
+func (c *realSecretClient) Close() error {
+	return c.client.Close()
+}
+
+// GCPBackend talks to GCP Secret Manager.
+type GCPBackend struct {
+	client    secretClient
+	projectId string
+}
+
+// NewGCPBackend creates a SecretBackend backed by GCP Secret Manager.
+func NewGCPBackend(ctx context.Context, projectId string) (*GCPBackend, error) {
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secretmanager client: %w", err)
+	}
+	return &GCPBackend{client: &realSecretClient{client: client}, projectId: projectId}, nil
+}
+
+// Close releases the underlying Secret Manager client, if it has one.
+func (b *GCPBackend) Close() error {
+	if closer, ok := b.client.(*realSecretClient); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (b *GCPBackend) ListVersions(ctx context.Context, vault string) (VersionIterator, error) {
+	parent := fmt.Sprintf("projects/%s/secrets/%s", b.projectId, vault)
+	versions, err := b.client.ListSecretVersions(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	return &sliceVersionIterator{versions: versions}, nil
+}
+
+func (b *GCPBackend) AccessVersion(ctx context.Context, name string) ([]byte, error) {
+	data, err := b.client.AccessSecretVersion(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access secret version: %w", err)
+	}
+	return data, nil
+}
+
+// ---- HashiCorp Vault KV v2 backend ----
+
+// VaultBackend talks to a HashiCorp Vault KV v2 mount, treating each
+// "vault" name (in our terminology) as a KV v2 secret path.
+type VaultBackend struct {
+	client *vaultapi.Client
+	mount  string
+}
+
+// NewVaultBackend creates a SecretBackend backed by a HashiCorp Vault KV v2
+// mount. It reads the standard VAULT_ADDR / VAULT_TOKEN environment
+// variables via the Vault client's default configuration.
+func NewVaultBackend(mount string) (*VaultBackend, error) {
+	client, err := vaultapi.NewClient(vaultapi.DefaultConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault client: %w", err)
+	}
+	return &VaultBackend{client: client, mount: mount}, nil
+}
+
+func (b *VaultBackend) ListVersions(ctx context.Context, vault string) (VersionIterator, error) {
+	metaPath := fmt.Sprintf("%s/metadata/%s", b.mount, vault)
+	secret, err := b.client.Logical().ReadWithContext(ctx, metaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault metadata %q: %w", metaPath, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("vault %q not found", vault)
+	}
+
+	versionsRaw, ok := secret.Data["versions"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("vault %q has no version metadata", vault)
+	}
+
+	versions := make([]Version, 0, len(versionsRaw))
+	for number, raw := range versionsRaw {
+		enabled := true
+		if meta, ok := raw.(map[string]interface{}); ok {
+			if destroyed, _ := meta["destroyed"].(bool); destroyed {
+				enabled = false
+			}
+			if deletionTime, _ := meta["deletion_time"].(string); deletionTime != "" {
+				enabled = false
+			}
+		}
+		versions = append(versions, Version{
+			Name:    fmt.Sprintf("%s/data/%s?version=%s", b.mount, vault, number),
+			Number:  number,
+			Enabled: enabled,
+		})
+	}
+
+	sortVersionsDescending(versions)
+
+	return &sliceVersionIterator{versions: versions}, nil
+}
+
+func (b *VaultBackend) AccessVersion(ctx context.Context, name string) ([]byte, error) {
+	path, version, err := splitVaultVersionedPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := b.client.Logical().ReadWithDataWithContext(ctx, path, map[string][]string{
+		"version": {version},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %q: %w", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("vault secret %q version %s not found", path, version)
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("vault secret %q version %s has no data", path, version)
+	}
+
+	return json.Marshal(data)
+}
+
+func splitVaultVersionedPath(name string) (path, version string, err error) {
+	parts := strings.SplitN(name, "?version=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("malformed vault version name %q", name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ---- local directory backend ----
+
+// FileBackend treats a directory as the set of vaults: each immediate
+// subdirectory is a vault, and each JSON file inside it is a version,
+// named after its version number and sorted numerically descending so the
+// highest-numbered file is treated as the newest version.
+type FileBackend struct {
+	rootDir string
+}
+
+// NewFileBackend creates a SecretBackend backed by a directory of JSON
+// blobs on disk, for tests and local development without GCP or Vault
+// credentials.
+func NewFileBackend(rootDir string) *FileBackend {
+	return &FileBackend{rootDir: rootDir}
+}
+
+func (b *FileBackend) ListVersions(ctx context.Context, vault string) (VersionIterator, error) {
+	vaultDir := filepath.Join(b.rootDir, vault)
+	entries, err := ioutil.ReadDir(vaultDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault directory %q: %w", vaultDir, err)
+	}
+
+	var versions []Version
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		name := filepath.Join(vaultDir, entry.Name())
+		versions = append(versions, Version{
+			Name:    name,
+			Number:  strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)),
+			Enabled: true,
+		})
+	}
+
+	sortVersionsDescending(versions)
+
+	return &sliceVersionIterator{versions: versions}, nil
+}
+
+func (b *FileBackend) AccessVersion(ctx context.Context, name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
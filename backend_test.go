@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeSecretClient is an in-memory secretClient for unit tests. versions
+// maps a vault's Secret Manager parent name to its list of versions, and
+// payloads maps a version's Name to its raw content. listErr/accessErr let
+// a test inject failures keyed the same way.
+type fakeSecretClient struct {
+	versions  map[string][]Version
+	payloads  map[string][]byte
+	listErr   map[string]error
+	accessErr map[string]error
+}
+
+func (c *fakeSecretClient) ListSecretVersions(ctx context.Context, parent string) ([]Version, error) {
+	if err, ok := c.listErr[parent]; ok {
+		return nil, err
+	}
+	return c.versions[parent], nil
+}
+
+func (c *fakeSecretClient) AccessSecretVersion(ctx context.Context, name string) ([]byte, error) {
+	if err, ok := c.accessErr[name]; ok {
+		return nil, err
+	}
+	return c.payloads[name], nil
+}
+
+func newTestBackend(client *fakeSecretClient) *GCPBackend {
+	return &GCPBackend{client: client, projectId: "test-project"}
+}
+
+func TestFindVaultVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		client     *fakeSecretClient
+		detector   DetectorInfo
+		wantErr    bool
+		wantVersions map[string]string // key name -> expected resolved version
+	}{
+		{
+			name:    "vault missing",
+			client:  &fakeSecretClient{listErr: map[string]error{"projects/test-project/secrets/detectors1": errors.New("not found")}},
+			detector: DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}}},
+			wantErr: true,
+		},
+		{
+			name: "no keys matched",
+			client: &fakeSecretClient{
+				versions: map[string][]Version{
+					"projects/test-project/secrets/detectors1": {
+						{Name: "v1", Number: "1", Enabled: true},
+					},
+				},
+				payloads: map[string][]byte{"v1": []byte(`{"OTHER_KEY":"x"}`)},
+			},
+			detector:     DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}}},
+			wantVersions: map[string]string{"API_KEY": ""},
+		},
+		{
+			name: "keys spread across multiple enabled versions",
+			client: &fakeSecretClient{
+				versions: map[string][]Version{
+					"projects/test-project/secrets/detectors1": {
+						{Name: "v2", Number: "2", Enabled: true},
+						{Name: "v1", Number: "1", Enabled: true},
+					},
+				},
+				payloads: map[string][]byte{
+					"v2": []byte(`{"API_KEY":"x"}`),
+					"v1": []byte(`{"API_SECRET":"y"}`),
+				},
+			},
+			detector:     DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}, {Name: "API_SECRET"}}},
+			wantVersions: map[string]string{"API_KEY": "2", "API_SECRET": "1"},
+		},
+		{
+			name: "disabled versions skipped",
+			client: &fakeSecretClient{
+				versions: map[string][]Version{
+					"projects/test-project/secrets/detectors1": {
+						{Name: "v2", Number: "2", Enabled: false},
+						{Name: "v1", Number: "1", Enabled: true},
+					},
+				},
+				payloads: map[string][]byte{
+					"v2": []byte(`{"API_KEY":"x"}`),
+					"v1": []byte(`{"API_KEY":"x"}`),
+				},
+			},
+			detector:     DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}}},
+			wantVersions: map[string]string{"API_KEY": "1"},
+		},
+		{
+			name: "key found inside a nested json payload",
+			client: &fakeSecretClient{
+				versions: map[string][]Version{
+					"projects/test-project/secrets/detectors1": {
+						{Name: "v1", Number: "1", Enabled: true},
+					},
+				},
+				payloads: map[string][]byte{"v1": []byte(`{"data":{"API_KEY":"x"}}`)},
+			},
+			detector:     DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}}},
+			wantVersions: map[string]string{"API_KEY": "1"},
+		},
+		{
+			name: "access version error",
+			client: &fakeSecretClient{
+				versions: map[string][]Version{
+					"projects/test-project/secrets/detectors1": {
+						{Name: "v1", Number: "1", Enabled: true},
+					},
+				},
+				accessErr: map[string]error{"v1": errors.New("access denied")},
+			},
+			detector: DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detectorVersionContent = &versionContentCache{data: make(map[string]string)}
+
+			backend := newTestBackend(tt.client)
+			detector := tt.detector
+			err := findVaultVersion(context.Background(), backend, &detector)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, key := range detector.Keys {
+				want, ok := tt.wantVersions[key.Name]
+				if !ok {
+					continue
+				}
+				if key.Version != want {
+					t.Errorf("key %s: got version %q, want %q", key.Name, key.Version, want)
+				}
+			}
+		})
+	}
+}
+
+// erroringVersionIterator yields a fixed slice of versions and then fails
+// with a non-ErrIteratorDone error, simulating a backend whose version
+// listing breaks mid-stream. sliceVersionIterator (used by every
+// production backend) can't produce this, since it's handed the whole
+// slice upfront and only ever runs out via ErrIteratorDone.
+type erroringVersionIterator struct {
+	versions []Version
+	pos      int
+	err      error
+}
+
+func (i *erroringVersionIterator) Next() (Version, error) {
+	if i.pos >= len(i.versions) {
+		return Version{}, i.err
+	}
+	v := i.versions[i.pos]
+	i.pos++
+	return v, nil
+}
+
+// erroringIteratorBackend is a SecretBackend whose ListVersions returns an
+// erroringVersionIterator, for exercising findVaultVersion's it.Next()
+// error branch directly.
+type erroringIteratorBackend struct {
+	versions []Version
+	err      error
+	payloads map[string][]byte
+}
+
+func (b *erroringIteratorBackend) ListVersions(ctx context.Context, vault string) (VersionIterator, error) {
+	return &erroringVersionIterator{versions: b.versions, err: b.err}, nil
+}
+
+func (b *erroringIteratorBackend) AccessVersion(ctx context.Context, name string) ([]byte, error) {
+	return b.payloads[name], nil
+}
+
+func TestFindVaultVersionIteratorError(t *testing.T) {
+	detectorVersionContent = &versionContentCache{data: make(map[string]string)}
+
+	backend := &erroringIteratorBackend{
+		versions: []Version{{Name: "v1", Number: "1", Enabled: true}},
+		err:      errors.New("stream reset"),
+		payloads: map[string][]byte{"v1": []byte(`{"OTHER_KEY":"x"}`)},
+	}
+	detector := DetectorInfo{Vault: "detectors1", Keys: []KeyInfo{{Name: "API_KEY"}}}
+
+	err := findVaultVersion(context.Background(), backend, &detector)
+	if err == nil {
+		t.Fatal("expected an error from a non-ErrIteratorDone iterator failure, got nil")
+	}
+	if errors.Is(err, ErrIteratorDone) {
+		t.Fatalf("got ErrIteratorDone, want the underlying iterator error to propagate: %v", err)
+	}
+}
+
+func TestGetVersionContentCachesPayload(t *testing.T) {
+	detectorVersionContent = &versionContentCache{data: make(map[string]string)}
+
+	calls := 0
+	client := &fakeSecretClient{
+		payloads: map[string][]byte{"v1": []byte("secret-data")},
+	}
+	backend := &cachingCountBackend{GCPBackend: newTestBackend(client), calls: &calls}
+
+	for i := 0; i < 2; i++ {
+		content, err := getVersionContent(context.Background(), backend, "v1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if content != "secret-data" {
+			t.Fatalf("got content %q, want %q", content, "secret-data")
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("AccessVersion called %d times, want 1 (cache should short-circuit)", calls)
+	}
+}
+
+// cachingCountBackend wraps a GCPBackend to count AccessVersion calls,
+// without touching the production backend's exported surface.
+type cachingCountBackend struct {
+	*GCPBackend
+	calls *int
+}
+
+func (b *cachingCountBackend) AccessVersion(ctx context.Context, name string) ([]byte, error) {
+	*b.calls++
+	return b.GCPBackend.AccessVersion(ctx, name)
+}
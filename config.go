@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config narrows which files, detectors, and keys a scan considers. It is
+// loaded from YAML or JSON via LoadConfig and layered on top of the legacy
+// filePrefix argument; a nil *Config means "no extra filtering".
+type Config struct {
+	IncludePaths       []string `yaml:"include_paths" json:"include_paths"`
+	ExcludePaths       []string `yaml:"exclude_paths" json:"exclude_paths"`
+	ExcludeExtensions  []string `yaml:"exclude_extensions" json:"exclude_extensions"`
+	IncludeDetectors   []string `yaml:"include_detectors" json:"include_detectors"`
+	ExcludeDetectors   []string `yaml:"exclude_detectors" json:"exclude_detectors"`
+	KeyAllowlist       []string `yaml:"key_allowlist" json:"key_allowlist"`
+	KeyDenylist        []string `yaml:"key_denylist" json:"key_denylist"`
+	BlacklistedStrings []string `yaml:"blacklisted_strings" json:"blacklisted_strings"`
+	VaultNamePattern   string   `yaml:"vault_name_pattern" json:"vault_name_pattern"`
+}
+
+// LoadConfig reads a Config from a YAML or JSON file, picked by extension
+// (".json" for JSON, anything else for YAML).
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %q: %w", path, err)
+	}
+
+	var cfg Config
+	if filepath.Ext(path) == ".json" {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %q as json: %w", path, err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %q as yaml: %w", path, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// AllowsPath reports whether path should be walked, given include_paths,
+// exclude_paths, and exclude_extensions. include_paths/exclude_paths match
+// as substrings of path, mirroring the simple prefix matching the tool
+// already used.
+func (c *Config) AllowsPath(path string) bool {
+	if c == nil {
+		return true
+	}
+
+	for _, excluded := range c.ExcludeExtensions {
+		if filepath.Ext(path) == excluded {
+			return false
+		}
+	}
+
+	for _, excluded := range c.ExcludePaths {
+		if strings.Contains(path, excluded) {
+			return false
+		}
+	}
+
+	if len(c.IncludePaths) == 0 {
+		return true
+	}
+	for _, included := range c.IncludePaths {
+		if strings.Contains(path, included) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsDetector reports whether a detector name passes include_detectors /
+// exclude_detectors, both matched as filepath.Match globs.
+func (c *Config) AllowsDetector(name string) bool {
+	if c == nil {
+		return true
+	}
+
+	if matchesAnyGlob(c.ExcludeDetectors, name) {
+		return false
+	}
+
+	if len(c.IncludeDetectors) == 0 {
+		return true
+	}
+	return matchesAnyGlob(c.IncludeDetectors, name)
+}
+
+// AllowsKey reports whether a key name passes key_allowlist, key_denylist,
+// and blacklisted_strings (substring match against the key name).
+func (c *Config) AllowsKey(name string) bool {
+	if c == nil {
+		return true
+	}
+
+	if matchesAnyGlob(c.KeyDenylist, name) {
+		return false
+	}
+	for _, blacklisted := range c.BlacklistedStrings {
+		if strings.Contains(name, blacklisted) {
+			return false
+		}
+	}
+
+	if len(c.KeyAllowlist) == 0 {
+		return true
+	}
+	return matchesAnyGlob(c.KeyAllowlist, name)
+}
+
+func matchesAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+func TestConfigAllowsPath(t *testing.T) {
+	cfg := &Config{
+		IncludePaths:      []string{"detectors/"},
+		ExcludePaths:      []string{"vendor/"},
+		ExcludeExtensions: []string{".md"},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"detectors/aws_test.go", true},
+		{"detectors/vendor/aws_test.go", false},
+		{"detectors/README.md", false},
+		{"other/aws_test.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.AllowsPath(tt.path); got != tt.want {
+			t.Errorf("AllowsPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestConfigAllowsDetector(t *testing.T) {
+	cfg := &Config{
+		IncludeDetectors: []string{"aws_*"},
+		ExcludeDetectors: []string{"aws_legacy"},
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"aws_s3", true},
+		{"aws_legacy", false},
+		{"gcp_storage", false},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.AllowsDetector(tt.name); got != tt.want {
+			t.Errorf("AllowsDetector(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigAllowsKey(t *testing.T) {
+	cfg := &Config{
+		KeyDenylist:        []string{"DEBUG_*"},
+		BlacklistedStrings: []string{"_LEGACY"},
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"API_KEY", true},
+		{"DEBUG_TOKEN", false},
+		{"API_KEY_LEGACY", false},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.AllowsKey(tt.name); got != tt.want {
+			t.Errorf("AllowsKey(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNilConfigAllowsEverything(t *testing.T) {
+	var cfg *Config
+	if !cfg.AllowsPath("anything") || !cfg.AllowsDetector("anything") || !cfg.AllowsKey("anything") {
+		t.Fatal("nil *Config should allow everything")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyExtractor finds candidate secret-key names referenced in a file's
+// contents, such as MustGetField("KEY") calls or env:"KEY" struct tags.
+type KeyExtractor interface {
+	Name() string
+	Extract(content string) []string
+}
+
+// regexExtractor is a KeyExtractor backed by a single regex whose
+// captureGroup'th submatch is the key name.
+type regexExtractor struct {
+	name         string
+	re           *regexp.Regexp
+	captureGroup int
+}
+
+func newRegexExtractor(name, pattern string, captureGroup int) (*regexExtractor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q for extractor %q: %w", pattern, name, err)
+	}
+	return &regexExtractor{name: name, re: re, captureGroup: captureGroup}, nil
+}
+
+func (e *regexExtractor) Name() string { return e.name }
+
+func (e *regexExtractor) Extract(content string) []string {
+	var keys []string
+	for _, match := range e.re.FindAllStringSubmatch(content, -1) {
+		if len(match) <= e.captureGroup {
+			continue
+		}
+		keys = append(keys, match[e.captureGroup])
+	}
+	return keys
+}
+
+// mustRegexExtractor builds a regexExtractor from a pattern that's a
+// compile-time constant in this file, not user input, so a compile
+// failure is a programmer error worth panicking on.
+func mustRegexExtractor(name, pattern string, captureGroup int) *regexExtractor {
+	e, err := newRegexExtractor(name, pattern, captureGroup)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// defaultKeyExtractors are the extractors shipped with the tool, covering
+// the conventions this repo's detector tests already use plus the common
+// env-var patterns seen in the wider ecosystem.
+func defaultKeyExtractors() []KeyExtractor {
+	return []KeyExtractor{
+		mustRegexExtractor("must_get_field", `MustGetField\("([A-Za-z0-9_]+)"\)?`, 1),
+		mustRegexExtractor("get_field", `\bGetField\("([A-Za-z0-9_]+)"\)?`, 1),
+		mustRegexExtractor("os_getenv", `os\.Getenv\("([A-Za-z0-9_]+)"\)`, 1),
+		mustRegexExtractor("common_env", `common\.Env\("([A-Za-z0-9_]+)"\)`, 1),
+		mustRegexExtractor("env_struct_tag", `env:"([A-Za-z0-9_]+)"`, 1),
+	}
+}
+
+const defaultVaultNamePattern = `detectors[1-5]`
+
+// KeyExtractorRegistry holds the set of extractors a scan runs, plus the
+// vault-name pattern used to spot a detector test's secret vault. Use
+// NewKeyExtractorRegistry for the built-ins, then optionally
+// SetVaultNamePattern and LoadExtractorPlugins to customize it.
+type KeyExtractorRegistry struct {
+	extractors  []KeyExtractor
+	vaultNameRE *regexp.Regexp
+}
+
+// NewKeyExtractorRegistry returns a registry seeded with defaultKeyExtractors
+// and the default detectors[1-5] vault-name pattern.
+func NewKeyExtractorRegistry() *KeyExtractorRegistry {
+	return &KeyExtractorRegistry{
+		extractors:  defaultKeyExtractors(),
+		vaultNameRE: regexp.MustCompile(`"(` + defaultVaultNamePattern + `)"`),
+	}
+}
+
+// SetVaultNamePattern overrides the regex used to spot a vault name in a
+// test file's source, e.g. "detectors[1-9]|secrets\\d+" for a repo whose
+// detector tests follow a different naming convention. pattern comes from
+// user config, so a malformed one is reported as an error rather than
+// panicking the tool.
+func (r *KeyExtractorRegistry) SetVaultNamePattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(`"(` + pattern + `)"`)
+	if err != nil {
+		return fmt.Errorf("invalid vault_name_pattern %q: %w", pattern, err)
+	}
+	r.vaultNameRE = re
+	return nil
+}
+
+// Register adds an extractor to the registry.
+func (r *KeyExtractorRegistry) Register(e KeyExtractor) {
+	r.extractors = append(r.extractors, e)
+}
+
+// ExtractKeys runs every registered extractor over content and returns the
+// keys they matched, in extractor-registration order.
+func (r *KeyExtractorRegistry) ExtractKeys(content string) []string {
+	var keys []string
+	for _, extractor := range r.extractors {
+		keys = append(keys, extractor.Extract(content)...)
+	}
+	return keys
+}
+
+// VaultName returns the single vault name referenced in content, or "" if
+// none or more than one match is found.
+func (r *KeyExtractorRegistry) VaultName(content string) string {
+	matches := r.vaultNameRE.FindAllStringSubmatch(content, -1)
+	if len(matches) == 1 && len(matches[0]) == 2 {
+		return matches[0][1]
+	}
+	return ""
+}
+
+// extractorPlugin describes a user-defined extractor loaded from a YAML
+// file under the plugin directory.
+type extractorPlugin struct {
+	Name         string `yaml:"name"`
+	Pattern      string `yaml:"pattern"`
+	CaptureGroup int    `yaml:"capture_group"`
+}
+
+// LoadExtractorPlugins reads every *.yaml file in dir and registers the
+// extractor it describes. A missing dir is not an error, since plugins are
+// opt-in.
+func (r *KeyExtractorRegistry) LoadExtractorPlugins(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read extractor plugin dir %q: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read extractor plugin %q: %w", path, err)
+		}
+
+		var plugin extractorPlugin
+		if err := yaml.Unmarshal(data, &plugin); err != nil {
+			return fmt.Errorf("failed to parse extractor plugin %q: %w", path, err)
+		}
+		if plugin.CaptureGroup == 0 {
+			plugin.CaptureGroup = 1
+		}
+
+		extractor, err := newRegexExtractor(plugin.Name, plugin.Pattern, plugin.CaptureGroup)
+		if err != nil {
+			return fmt.Errorf("failed to load extractor plugin %q: %w", path, err)
+		}
+		r.Register(extractor)
+	}
+
+	return nil
+}
+
+// DefaultExtractorPluginDir returns ~/.config/gcp-secret-finder/extractors,
+// or "" if the user's home directory can't be determined.
+func DefaultExtractorPluginDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcp-secret-finder", "extractors")
+}
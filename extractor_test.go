@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestKeyExtractorRegistryExtractKeys(t *testing.T) {
+	content := `
+		apiKey := MustGetField("API_KEY")
+		secret := GetField("API_SECRET")
+		token := os.Getenv("AUTH_TOKEN")
+		host := common.Env("HOST_URL")
+
+		type cfg struct {
+			Password string ` + "`env:\"PASSWORD\"`" + `
+		}
+	`
+
+	registry := NewKeyExtractorRegistry()
+	got := registry.ExtractKeys(content)
+	sort.Strings(got)
+
+	want := []string{"API_KEY", "API_SECRET", "AUTH_TOKEN", "HOST_URL", "PASSWORD"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ExtractKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestKeyExtractorRegistryVaultNamePattern(t *testing.T) {
+	registry := NewKeyExtractorRegistry()
+
+	if got := registry.VaultName(`"secrets42"`); got != "" {
+		t.Fatalf("VaultName() = %q before override, want empty", got)
+	}
+
+	if err := registry.SetVaultNamePattern(`detectors[1-9]|secrets\d+`); err != nil {
+		t.Fatalf("SetVaultNamePattern() = %v, want nil", err)
+	}
+	if got := registry.VaultName(`"secrets42"`); got != "secrets42" {
+		t.Fatalf("VaultName() = %q after override, want %q", got, "secrets42")
+	}
+}
+
+func TestKeyExtractorRegistrySetVaultNamePatternInvalid(t *testing.T) {
+	registry := NewKeyExtractorRegistry()
+	if err := registry.SetVaultNamePattern(`detectors[1-`); err == nil {
+		t.Fatal("SetVaultNamePattern() with a malformed regex should return an error, not panic")
+	}
+}
+
+func TestKeyExtractorRegistryLoadExtractorPluginsMissingDir(t *testing.T) {
+	registry := NewKeyExtractorRegistry()
+	if err := registry.LoadExtractorPlugins("/no/such/dir"); err != nil {
+		t.Fatalf("LoadExtractorPlugins() on missing dir = %v, want nil", err)
+	}
+}
+
+func TestKeyExtractorRegistryLoadExtractorPluginsInvalidPattern(t *testing.T) {
+	dir := t.TempDir()
+	pluginPath := filepath.Join(dir, "broken.yaml")
+	contents := "name: broken\npattern: \"detectors[1-\"\ncapture_group: 1\n"
+	if err := os.WriteFile(pluginPath, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write plugin fixture: %v", err)
+	}
+
+	registry := NewKeyExtractorRegistry()
+	if err := registry.LoadExtractorPlugins(dir); err == nil {
+		t.Fatal("LoadExtractorPlugins() with a malformed plugin regex should return an error, not panic")
+	}
+}
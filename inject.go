@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// runInject implements the `inject` subcommand: resolve every detector's
+// MustGetField("KEY") calls to a concrete value pulled from the configured
+// secret backend, then either write them to a .env/json/dotenv file or
+// patch the literal values straight into the Go source for local
+// debugging.
+func runInject(args []string) {
+	fs := flag.NewFlagSet("inject", flag.ExitOnError)
+	backendFlag := fs.String("backend", "gcp", "secret backend to use: gcp, vault, or file")
+	configFlag := fs.String("config", "", "path to a YAML/JSON config file with include/exclude filters (optional)")
+	formatFlag := fs.String("format", "env", "output format when not patching in place: env, dotenv, or json")
+	outputFlag := fs.String("output", "secrets.env", "output file path when not patching in place")
+	patchFlag := fs.Bool("patch", false, "rewrite MustGetField(\"KEY\") calls in the Go test files with resolved literals instead of writing an output file")
+	fs.Parse(args)
+
+	positional := fs.Args()
+	if len(positional) < 2 {
+		log.Fatalf("Usage: %s inject [--backend=gcp|vault|file] [--config=path] [--format=env|dotenv|json] [--output=path] [--patch] <directory_path> <gcp_project_name|vault_mount|file_root> <prefix>(optional)  ...]", os.Args[0])
+	}
+
+	pkgDir := positional[0]
+	projectId := positional[1]
+	filePrefix := ""
+	if len(positional) > 2 {
+		filePrefix = positional[2]
+	}
+
+	cfg := loadOptionalConfig(*configFlag)
+	testFilePaths, err := getIntegrationTestFilePaths(pkgDir, filePrefix, cfg)
+	if err != nil {
+		log.Fatalf("failed to get test file paths: %v", err)
+	}
+
+	registry := newConfiguredRegistry(cfg)
+	detectorInfos, err := extractKeysFromFile(testFilePaths, cfg, registry)
+	if err != nil {
+		log.Fatalf("failed to extract keys from file: %v", err)
+	}
+
+	ctx := context.Background()
+
+	backend, closeBackend, err := newSecretBackend(ctx, *backendFlag, projectId)
+	if err != nil {
+		log.Fatalf("failed to create %s backend: %v", *backendFlag, err)
+	}
+	defer closeBackend()
+
+	for i := range detectorInfos {
+		if len(detectorInfos[i].Keys) == 0 {
+			continue
+		}
+		if err := findVaultVersion(ctx, backend, &detectorInfos[i]); err != nil {
+			log.Println(fmt.Sprintf("failed to find vault version: %v", err))
+		}
+	}
+
+	if *patchFlag {
+		if err := patchTestFiles(detectorInfos); err != nil {
+			log.Fatalf("failed to patch test files: %v", err)
+		}
+		return
+	}
+
+	if err := writeInjectedValues(detectorInfos, *formatFlag, *outputFlag); err != nil {
+		log.Fatalf("failed to write injected values: %v", err)
+	}
+	fmt.Printf("Wrote resolved secrets to %s\n", *outputFlag)
+}
+
+// writeInjectedValues writes each detector's resolved KEY=value pairs to
+// outputPath in the given format (env, dotenv, or json). Keys that didn't
+// resolve to a value are skipped.
+func writeInjectedValues(detectorInfos []DetectorInfo, format, outputPath string) error {
+	var content string
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(injectedValuesByDetector(detectorInfos), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal injected values: %w", err)
+		}
+		content = string(data) + "\n"
+	case "env", "dotenv":
+		content = injectedValuesAsEnv(detectorInfos)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	return ioutil.WriteFile(outputPath, []byte(content), 0600)
+}
+
+// injectedValuesByDetector groups each detector's resolved key values for
+// JSON output: {"detectorName": {"KEY": "value", ...}, ...}.
+func injectedValuesByDetector(detectorInfos []DetectorInfo) map[string]map[string]string {
+	result := make(map[string]map[string]string, len(detectorInfos))
+	for _, detectorInfo := range detectorInfos {
+		values := make(map[string]string)
+		for _, key := range detectorInfo.Keys {
+			if key.Value == "" {
+				continue
+			}
+			values[key.Name] = key.Value
+		}
+		if len(values) > 0 {
+			result[detectorInfo.Name] = values
+		}
+	}
+	return result
+}
+
+// injectedValuesAsEnv renders each detector's resolved key values as a
+// dotenv-style block, with a comment header naming the detector.
+func injectedValuesAsEnv(detectorInfos []DetectorInfo) string {
+	var out []byte
+	for _, detectorInfo := range detectorInfos {
+		var lines []string
+		for _, key := range detectorInfo.Keys {
+			if key.Value == "" {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s=%s", key.Name, key.Value))
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		sort.Strings(lines)
+
+		out = append(out, []byte(fmt.Sprintf("# %s\n", detectorInfo.Name))...)
+		for _, line := range lines {
+			out = append(out, []byte(line+"\n")...)
+		}
+		out = append(out, '\n')
+	}
+	return string(out)
+}
+
+// patchTestFiles rewrites MustGetField("KEY") calls in the detectors' test
+// files with the resolved literal value, for local debugging only. Keys
+// that didn't resolve are left untouched.
+func patchTestFiles(detectorInfos []DetectorInfo) error {
+	valuesByFile := make(map[string]map[string]string, len(detectorInfos))
+	for _, detectorInfo := range detectorInfos {
+		if detectorInfo.FilePath == "" {
+			continue
+		}
+		values := make(map[string]string)
+		for _, key := range detectorInfo.Keys {
+			if key.Value != "" {
+				values[key.Name] = key.Value
+			}
+		}
+		if len(values) > 0 {
+			valuesByFile[detectorInfo.FilePath] = values
+		}
+	}
+
+	for path, values := range valuesByFile {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		patched := patchMustGetFieldCalls(string(content), values)
+		if patched == string(content) {
+			continue
+		}
+
+		if err := ioutil.WriteFile(path, []byte(patched), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var mustGetFieldRE = regexp.MustCompile(`MustGetField\("([A-Za-z0-9_]+)"\)`)
+
+// patchMustGetFieldCalls replaces every MustGetField("KEY") call in content
+// with a quoted string literal of values["KEY"], for keys that resolved.
+func patchMustGetFieldCalls(content string, values map[string]string) string {
+	return mustGetFieldRE.ReplaceAllStringFunc(content, func(match string) string {
+		submatches := mustGetFieldRE.FindStringSubmatch(match)
+		if len(submatches) != 2 {
+			return match
+		}
+		value, ok := values[submatches[1]]
+		if !ok {
+			return match
+		}
+		return strconv.Quote(value)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestInjectedValuesAsEnv(t *testing.T) {
+	detectorInfos := []DetectorInfo{
+		{
+			Name: "aws",
+			Keys: []KeyInfo{
+				{Name: "API_KEY", Value: "abc"},
+				{Name: "API_SECRET", Value: ""},
+			},
+		},
+	}
+
+	got := injectedValuesAsEnv(detectorInfos)
+	want := "# aws\nAPI_KEY=abc\n\n"
+	if got != want {
+		t.Fatalf("injectedValuesAsEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestInjectedValuesByDetector(t *testing.T) {
+	detectorInfos := []DetectorInfo{
+		{
+			Name: "aws",
+			Keys: []KeyInfo{
+				{Name: "API_KEY", Value: "abc"},
+				{Name: "API_SECRET", Value: ""},
+			},
+		},
+		{Name: "gcp", Keys: []KeyInfo{{Name: "UNRESOLVED"}}},
+	}
+
+	got := injectedValuesByDetector(detectorInfos)
+	if len(got) != 1 || got["aws"]["API_KEY"] != "abc" {
+		t.Fatalf("injectedValuesByDetector() = %v", got)
+	}
+	if _, ok := got["gcp"]; ok {
+		t.Fatalf("detector with no resolved keys should be omitted, got %v", got["gcp"])
+	}
+}
+
+func TestPatchMustGetFieldCalls(t *testing.T) {
+	content := `key := MustGetField("API_KEY")
+	unresolved := MustGetField("API_SECRET")`
+
+	got := patchMustGetFieldCalls(content, map[string]string{"API_KEY": "abc123"})
+	want := `key := "abc123"
+	unresolved := MustGetField("API_SECRET")`
+
+	if got != want {
+		t.Fatalf("patchMustGetFieldCalls() = %q, want %q", got, want)
+	}
+}
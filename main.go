@@ -2,73 +2,217 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
-
-	secretmanager "cloud.google.com/go/secretmanager/apiv1"
-	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
-	"google.golang.org/api/iterator"
+	"sync"
 )
 
 type KeyInfo struct {
 	Name    string
 	Version string
+	Value   string
 }
 
 type DetectorInfo struct {
-	Name  string
-	Vault string
-	Keys  []KeyInfo
+	Name     string
+	Vault    string
+	Keys     []KeyInfo
+	FilePath string
+}
+
+// versionContentCache is a concurrency-safe cache of version name -> raw
+// secret payload, shared by every in-flight findVaultVersion call when
+// scans run with concurrency > 1.
+type versionContentCache struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (c *versionContentCache) get(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, ok := c.data[name]
+	return content, ok
+}
+
+func (c *versionContentCache) set(name, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[name] = content
 }
 
-var detectorVersionContent = make(map[string]string)
+var detectorVersionContent = &versionContentCache{data: make(map[string]string)}
 
 func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("Usage: %s <scan|inject> ...", os.Args[0])
+	}
 
-	if len(os.Args) < 4 {
-		log.Fatalf("Usage: %s <directory_path> <gcp_project_name> <prefix>(optional)  ...]", os.Args[0])
+	switch os.Args[1] {
+	case "scan":
+		runScan(os.Args[2:])
+	case "inject":
+		runInject(os.Args[2:])
+	default:
+		// Back-compat: the tool used to take <directory> <project> <prefix>
+		// with no subcommand at all. Treat an unrecognized first arg as the
+		// start of a scan invocation rather than breaking existing callers.
+		runScan(os.Args[1:])
+	}
+}
+
+// runScan implements the `scan` subcommand: walk detector test files,
+// extract the keys they reference, and report which Secret Manager (or
+// Vault, or local-file) version each one resolves to.
+func runScan(args []string) {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	backendFlag := fs.String("backend", "gcp", "secret backend to use: gcp, vault, or file")
+	configFlag := fs.String("config", "", "path to a YAML/JSON config file with include/exclude filters (optional)")
+	outputFlag := fs.String("output", "text", "output format: text, json, or sarif")
+	concurrencyFlag := fs.Int("concurrency", runtime.NumCPU(), "number of detectors to resolve in parallel")
+	fs.Parse(args)
+
+	positional := fs.Args()
+	if len(positional) < 2 {
+		log.Fatalf("Usage: %s scan [--backend=gcp|vault|file] [--config=path] [--output=text|json|sarif] [--concurrency=N] <directory_path> <gcp_project_name|vault_mount|file_root> <prefix>(optional)  ...]", os.Args[0])
 	}
 
-	fmt.Println("Starting ....")
+	log.Println("Starting ....")
 
-	// detector directory path
-	pkgDir := os.Args[1]
-	projectId := os.Args[2]
-	filePrefix := os.Args[3]
+	pkgDir := positional[0]
+	projectId := positional[1]
+	filePrefix := ""
+	if len(positional) > 2 {
+		filePrefix = positional[2]
+	}
 
-	testFilePaths, err := getIntegrationTestFilePaths(pkgDir, filePrefix)
+	cfg := loadOptionalConfig(*configFlag)
+	testFilePaths, err := getIntegrationTestFilePaths(pkgDir, filePrefix, cfg)
 	if err != nil {
 		log.Fatalf("failed to get test file paths: %v", err)
 	}
 
-	detectorInfos, err := extractKeysFromFile(testFilePaths)
+	registry := newConfiguredRegistry(cfg)
+	detectorInfos, err := extractKeysFromFile(testFilePaths, cfg, registry)
 	if err != nil {
 		log.Fatalf("failed to extract keys from file: %v", err)
 	}
 
 	ctx := context.Background()
-	client, err := secretmanager.NewClient(ctx)
+
+	backend, closeBackend, err := newSecretBackend(ctx, *backendFlag, projectId)
 	if err != nil {
-		log.Fatalf("failed to create secretmanager client: %v", err)
+		log.Fatalf("failed to create %s backend: %v", *backendFlag, err)
 	}
-	defer client.Close()
+	defer closeBackend()
+
+	resolved := resolveDetectors(ctx, backend, detectorInfos, *concurrencyFlag)
 
-	for _, detectorInfo := range detectorInfos {
-		if len(detectorInfo.Keys) > 0 {
-			err := findVaultVersion(ctx, client, projectId, &detectorInfo)
-			if err != nil {
+	if err := writeReport(resolved, *outputFlag); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
+
+// resolveDetectors runs findVaultVersion over every detector with a
+// bounded pool of concurrency goroutines, since each call issues many
+// network round-trips and detector test files in a large repo are
+// otherwise processed strictly one at a time.
+func resolveDetectors(ctx context.Context, backend SecretBackend, detectorInfos []DetectorInfo, concurrency int) []DetectorInfo {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]DetectorInfo, len(detectorInfos))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, detectorInfo := range detectorInfos {
+		results[i] = detectorInfo
+		if len(detectorInfo.Keys) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, detectorInfo DetectorInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := findVaultVersion(ctx, backend, &detectorInfo); err != nil {
 				log.Println(fmt.Sprintf("failed to find vault version: %v", err))
-				continue
 			}
-			printDetectorInfo(detectorInfo)
+			results[i] = detectorInfo
+		}(i, detectorInfo)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// loadOptionalConfig loads the config at path, or returns nil if path is
+// empty, mirroring the tool's "no filters" default.
+func loadOptionalConfig(path string) *Config {
+	if path == "" {
+		return nil
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	return cfg
+}
+
+// newConfiguredRegistry builds a KeyExtractorRegistry seeded with cfg's
+// vault-name pattern override (if any) and any user-defined extractor
+// plugins found on disk.
+func newConfiguredRegistry(cfg *Config) *KeyExtractorRegistry {
+	registry := NewKeyExtractorRegistry()
+	if cfg != nil {
+		if err := registry.SetVaultNamePattern(cfg.VaultNamePattern); err != nil {
+			log.Fatalf("invalid config: %v", err)
+		}
+	}
+	if pluginDir := DefaultExtractorPluginDir(); pluginDir != "" {
+		if err := registry.LoadExtractorPlugins(pluginDir); err != nil {
+			log.Printf("failed to load extractor plugins: %v", err)
 		}
 	}
+	return registry
+}
+
+// newSecretBackend builds the SecretBackend named by backendName. arg is the
+// GCP project ID, the Vault mount path, or the local directory root,
+// depending on which backend was selected. The returned close func releases
+// any backend resources and is always safe to defer.
+func newSecretBackend(ctx context.Context, backendName, arg string) (SecretBackend, func() error, error) {
+	noop := func() error { return nil }
+
+	switch backendName {
+	case "gcp":
+		backend, err := NewGCPBackend(ctx, arg)
+		if err != nil {
+			return nil, noop, err
+		}
+		return backend, backend.Close, nil
+	case "vault":
+		backend, err := NewVaultBackend(arg)
+		if err != nil {
+			return nil, noop, err
+		}
+		return backend, noop, nil
+	case "file":
+		return NewFileBackend(arg), noop, nil
+	default:
+		return nil, noop, fmt.Errorf("unknown backend %q", backendName)
+	}
 }
 
 func printDetectorInfo(detectorInfo DetectorInfo) {
@@ -84,13 +228,17 @@ func printDetectorInfo(detectorInfo DetectorInfo) {
 	fmt.Println("---------------")
 }
 
-func getIntegrationTestFilePaths(pkgDir, filePrefix string) ([]string, error) {
+func getIntegrationTestFilePaths(pkgDir, filePrefix string, cfg *Config) ([]string, error) {
 	var testFilePaths []string
 	err := filepath.Walk(pkgDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		if !cfg.AllowsPath(path) {
+			return nil
+		}
+
 		if filePrefix == "" || strings.HasPrefix(info.Name(), filePrefix) {
 			if strings.HasSuffix(path, "_test.go") {
 				testFilePaths = append(testFilePaths, path)
@@ -104,7 +252,7 @@ func getIntegrationTestFilePaths(pkgDir, filePrefix string) ([]string, error) {
 	return testFilePaths, nil
 }
 
-func extractKeysFromFile(testFilePaths []string) ([]DetectorInfo, error) {
+func extractKeysFromFile(testFilePaths []string, cfg *Config, registry *KeyExtractorRegistry) ([]DetectorInfo, error) {
 
 	var results []DetectorInfo
 	for _, testFilePath := range testFilePaths {
@@ -114,28 +262,23 @@ func extractKeysFromFile(testFilePaths []string) ([]DetectorInfo, error) {
 		}
 
 		var detectorInfo DetectorInfo
+		detectorInfo.FilePath = testFilePath
 
 		// filter out _test.go or _integration_test.go from the file name
 		detectorInfo.Name = strings.TrimSuffix(filepath.Base(testFilePath), "_test.go")
 		detectorInfo.Name = strings.TrimSuffix(detectorInfo.Name, "_integration")
 
-		vaultNameRE := regexp.MustCompile(`"(detectors[1-5])"`)
-		vaultMatches := vaultNameRE.FindAllStringSubmatch(string(content), -1)
-
-		if len(vaultMatches) == 1 && len(vaultMatches[0]) == 2 {
-			detectorInfo.Vault = vaultMatches[0][1]
+		if !cfg.AllowsDetector(detectorInfo.Name) {
+			continue
 		}
 
-		keyRE := regexp.MustCompile(`MustGetField\("([A-Za-z0-9_]+)"\)?`)
-		keyMatches := keyRE.FindAllStringSubmatch(string(content), -1)
+		detectorInfo.Vault = registry.VaultName(string(content))
 
-		for _, match := range keyMatches {
-			if len(match) != 2 {
+		for _, key := range registry.ExtractKeys(string(content)) {
+			if !cfg.AllowsKey(key) {
 				continue
 			}
-			var keyInfo KeyInfo
-			keyInfo.Name = match[1]
-			detectorInfo.Keys = append(detectorInfo.Keys, keyInfo)
+			detectorInfo.Keys = append(detectorInfo.Keys, KeyInfo{Name: key})
 		}
 
 		results = append(results, detectorInfo)
@@ -154,28 +297,43 @@ func getVersionNumber(name string) string {
 	return versionNumber
 }
 
-func getVersionContent(ctx context.Context, client *secretmanager.Client, name string) (string, error) {
+// decodeSecretFields parses a JSON object secret payload into a flat
+// string-to-string map so callers can look up a key's actual value
+// instead of just checking whether its name appears in the payload. It
+// returns nil if secretData isn't a JSON object, letting callers fall
+// back to substring matching for non-JSON payloads.
+func decodeSecretFields(secretData string) map[string]string {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(secretData), &raw); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		fields[key] = fmt.Sprintf("%v", value)
+	}
+	return fields
+}
+
+func getVersionContent(ctx context.Context, backend SecretBackend, name string) (string, error) {
 
-	if content, exits := detectorVersionContent[name]; exits {
+	if content, ok := detectorVersionContent.get(name); ok {
 		return content, nil
 	}
 
-	// Access the secret version
-	accessReq := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: name,
-	}
-	result, err := client.AccessSecretVersion(ctx, accessReq)
+	data, err := backend.AccessVersion(ctx, name)
 	if err != nil {
 		log.Printf("failed to access secret version: %v", err)
 		return "", err
 	}
 
-	detectorVersionContent[name] = string(result.Payload.Data)
+	content := string(data)
+	detectorVersionContent.set(name, content)
 
-	return detectorVersionContent[name], nil
+	return content, nil
 }
 
-func findVaultVersion(ctx context.Context, client *secretmanager.Client, projectId string, detectorInfo *DetectorInfo) error {
+func findVaultVersion(ctx context.Context, backend SecretBackend, detectorInfo *DetectorInfo) error {
 
 	if detectorInfo.Vault == "" {
 		return errors.New("vault name is empty")
@@ -183,16 +341,15 @@ func findVaultVersion(ctx context.Context, client *secretmanager.Client, project
 
 	foundKeys := make([]string, 0, len(detectorInfo.Keys))
 
-	// List all versions of the secret
-	req := &secretmanagerpb.ListSecretVersionsRequest{
-		Parent: fmt.Sprintf("projects/%s/secrets/%s", projectId, detectorInfo.Vault),
+	it, err := backend.ListVersions(ctx, detectorInfo.Vault)
+	if err != nil {
+		return err
 	}
-	it := client.ListSecretVersions(ctx, req)
 
 	for {
 		version, err := it.Next()
 		if err != nil {
-			if errors.Is(err, iterator.Done) {
+			if errors.Is(err, ErrIteratorDone) {
 				return nil
 			}
 
@@ -200,19 +357,31 @@ func findVaultVersion(ctx context.Context, client *secretmanager.Client, project
 		}
 
 		// if version state is non enabled, skip
-		if version.State != secretmanagerpb.SecretVersion_ENABLED {
+		if !version.Enabled {
 			continue
 		}
-		fmt.Println("Checking version: ", version.Name)
+		log.Println("Checking version: ", version.Name)
 
 		// Check if the secret contains any of the keys
-		secretData, err := getVersionContent(ctx, client, version.Name)
+		secretData, err := getVersionContent(ctx, backend, version.Name)
 		if err != nil {
 			return err
 		}
+		fields := decodeSecretFields(secretData)
 		for i, key := range detectorInfo.Keys {
-			if strings.Contains(secretData, key.Name) {
-				detectorInfo.Keys[i].Version = getVersionNumber(version.Name)
+			value, found := fields[key.Name]
+			if !found {
+				// Either the payload isn't a JSON object (e.g. a raw blob)
+				// or the key lives in a nested object rather than at the
+				// top level; either way fall back to the old presence
+				// check so a key that used to resolve still does. The
+				// substring match can't recover the value itself, so Value
+				// stays empty for these.
+				found = strings.Contains(secretData, key.Name)
+			}
+			if found {
+				detectorInfo.Keys[i].Version = version.Number
+				detectorInfo.Keys[i].Value = value
 				foundKeys = append(foundKeys, key.Name)
 			}
 		}
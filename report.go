@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DetectorReport is the JSON-friendly form of a DetectorInfo's scan result.
+type DetectorReport struct {
+	Detector string      `json:"detector"`
+	Vault    string      `json:"vault"`
+	Keys     []KeyReport `json:"keys"`
+}
+
+// KeyReport is the JSON-friendly form of a KeyInfo's scan result.
+type KeyReport struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Found   bool   `json:"found"`
+}
+
+// buildDetectorReports converts the scanned DetectorInfos into the
+// structured form shared by the json and sarif output formats.
+func buildDetectorReports(detectorInfos []DetectorInfo) []DetectorReport {
+	var reports []DetectorReport
+	for _, detectorInfo := range detectorInfos {
+		if len(detectorInfo.Keys) == 0 {
+			continue
+		}
+		report := DetectorReport{Detector: detectorInfo.Name, Vault: detectorInfo.Vault}
+		for _, key := range detectorInfo.Keys {
+			report.Keys = append(report.Keys, KeyReport{
+				Name:    key.Name,
+				Version: key.Version,
+				Found:   key.Version != "",
+			})
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// writeReport renders detectorInfos in the given format (text, json, or
+// sarif) to stdout.
+func writeReport(detectorInfos []DetectorInfo, format string) error {
+	switch format {
+	case "", "text":
+		for _, detectorInfo := range detectorInfos {
+			if len(detectorInfo.Keys) > 0 {
+				printDetectorInfo(detectorInfo)
+			}
+		}
+		return nil
+	case "json":
+		data, err := json.MarshalIndent(buildDetectorReports(detectorInfos), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json report: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "sarif":
+		data, err := json.MarshalIndent(buildSarifLog(detectorInfos), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal sarif report: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// Minimal SARIF 2.1.0 types, just deep enough to report not-found keys as
+// warnings so CI systems can consume scan results.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+const sarifSchemaURI = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+// buildSarifLog wraps every not-found key as a SARIF warning result, so CI
+// systems already wired up for SARIF can flag missing secrets the same way
+// they flag lint findings.
+func buildSarifLog(detectorInfos []DetectorInfo) sarifLog {
+	var results []sarifResult
+	for _, detectorInfo := range detectorInfos {
+		for _, key := range detectorInfo.Keys {
+			if key.Version != "" {
+				continue
+			}
+
+			result := sarifResult{
+				RuleID:  "secret-key-not-found",
+				Level:   "warning",
+				Message: sarifMessage{Text: fmt.Sprintf("key %q for detector %q was not found in vault %q", key.Name, detectorInfo.Name, detectorInfo.Vault)},
+			}
+			if detectorInfo.FilePath != "" {
+				result.Locations = []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: detectorInfo.FilePath},
+					},
+				}}
+			}
+			results = append(results, result)
+		}
+	}
+
+	return sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "gcp-secret-finder"}},
+			Results: results,
+		}},
+	}
+}
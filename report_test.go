@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildDetectorReports(t *testing.T) {
+	detectorInfos := []DetectorInfo{
+		{
+			Name:  "aws",
+			Vault: "detectors1",
+			Keys: []KeyInfo{
+				{Name: "API_KEY", Version: "2"},
+				{Name: "API_SECRET"},
+			},
+		},
+		{Name: "empty"},
+	}
+
+	reports := buildDetectorReports(detectorInfos)
+	if len(reports) != 1 {
+		t.Fatalf("buildDetectorReports() returned %d reports, want 1 (empty detector should be skipped)", len(reports))
+	}
+
+	report := reports[0]
+	if report.Detector != "aws" || report.Vault != "detectors1" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !report.Keys[0].Found || report.Keys[0].Version != "2" {
+		t.Fatalf("expected API_KEY found at version 2, got %+v", report.Keys[0])
+	}
+	if report.Keys[1].Found {
+		t.Fatalf("expected API_SECRET not found, got %+v", report.Keys[1])
+	}
+}
+
+func TestBuildSarifLogOnlyReportsNotFoundKeys(t *testing.T) {
+	detectorInfos := []DetectorInfo{
+		{
+			Name:     "aws",
+			Vault:    "detectors1",
+			FilePath: "aws_test.go",
+			Keys: []KeyInfo{
+				{Name: "API_KEY", Version: "2"},
+				{Name: "API_SECRET"},
+			},
+		},
+	}
+
+	sarif := buildSarifLog(detectorInfos)
+	if len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != 1 {
+		t.Fatalf("expected exactly one SARIF result for the not-found key, got %+v", sarif.Runs)
+	}
+
+	result := sarif.Runs[0].Results[0]
+	if !strings.Contains(result.Message.Text, "API_SECRET") {
+		t.Fatalf("expected SARIF message to mention API_SECRET, got %q", result.Message.Text)
+	}
+	if result.Locations[0].PhysicalLocation.ArtifactLocation.URI != "aws_test.go" {
+		t.Fatalf("unexpected SARIF location: %+v", result.Locations)
+	}
+}
+
+func TestWriteReportUnknownFormat(t *testing.T) {
+	if err := writeReport(nil, "yaml"); err == nil {
+		t.Fatal("expected an error for an unknown output format")
+	}
+}